@@ -0,0 +1,228 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/snapcore/spread/spread"
+	"github.com/ulikunitz/xz"
+)
+
+// downloadSpec is one "src-dir:dst-dir" pair passed via -download.
+type downloadSpec struct {
+	source string
+	dest   string
+}
+
+// downloadSpecs collects every -download flag occurrence, making it
+// repeatable instead of accepting only a single pair.
+type downloadSpecs []downloadSpec
+
+func (d *downloadSpecs) String() string {
+	parts := make([]string, len(*d))
+	for i, spec := range *d {
+		parts[i] = spec.source + ":" + spec.dest
+	}
+	return strings.Join(parts, ",")
+}
+
+func (d *downloadSpecs) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("expected <src-dir>:<dst-dir>, got %q", value)
+	}
+	*d = append(*d, downloadSpec{source: parts[0], dest: parts[1]})
+	return nil
+}
+
+var downloads downloadSpecs
+
+func init() {
+	flag.Var(&downloads, "download", "Download contents of src-dir to dst-dir after all jobs have finished for each suite (Usage: -download <src-dir>:<dst-dir>, may be repeated)")
+}
+
+var downloadFormat = flag.String("download-format", "xz", "Archive codec used by -download: xz, gz, zstd, or none")
+
+// nopCloser adapts an io.Reader with no Close of its own (xz.Reader, or
+// the raw source for "none") to io.ReadCloser, so decompressor has one
+// return type regardless of codec.
+type nopCloser struct {
+	io.Reader
+}
+
+func (nopCloser) Close() error { return nil }
+
+// decompressor wraps r with the reader for the given -download-format. The
+// returned ReadCloser must be closed once the caller is done with it: the
+// zstd codec in particular holds decoder goroutines and buffers open until
+// Close is called.
+func decompressor(format string, r io.Reader) (io.ReadCloser, error) {
+	switch format {
+	case "xz":
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return nopCloser{xr}, nil
+	case "gz", "gzip":
+		return gzip.NewReader(r)
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case "none", "":
+		return nopCloser{r}, nil
+	default:
+		return nil, fmt.Errorf("unsupported -download-format %q", format)
+	}
+}
+
+// extractTar streams tarball entries from r directly onto disk under dest,
+// in-process, so a corrupt or truncated archive surfaces as a Go error with
+// context instead of opaque tar(1) stderr.
+func extractTar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read tar entry: %v", err)
+		}
+
+		path := filepath.Join(dest, filepath.Clean("/"+hdr.Name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, os.FileMode(hdr.Mode)); err != nil {
+				return fmt.Errorf("cannot create %s: %v", path, err)
+			}
+		case tar.TypeSymlink:
+			// hdr.Linkname is attacker-controlled; a relative or
+			// absolute target that escapes dest would let a later
+			// entry write through the link to anywhere on disk
+			// (the classic tar symlink-slip).
+			if err := checkLinkTarget(dest, filepath.Dir(path), hdr.Linkname); err != nil {
+				return fmt.Errorf("refusing to extract symlink %s: %v", hdr.Name, err)
+			}
+			if err := os.Symlink(hdr.Linkname, path); err != nil {
+				return fmt.Errorf("cannot create symlink %s: %v", path, err)
+			}
+		case tar.TypeLink:
+			// hdr.Linkname for a hardlink is archive-root-relative,
+			// unlike a symlink's target, so it's resolved against
+			// dest rather than the entry's own directory.
+			oldpath := filepath.Join(dest, filepath.Clean("/"+hdr.Linkname))
+			if err := checkLinkTarget(dest, dest, hdr.Linkname); err != nil {
+				return fmt.Errorf("refusing to extract hardlink %s: %v", hdr.Name, err)
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("cannot create %s: %v", filepath.Dir(path), err)
+			}
+			if err := os.Link(oldpath, path); err != nil {
+				return fmt.Errorf("cannot create hardlink %s: %v", path, err)
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("cannot create %s: %v", filepath.Dir(path), err)
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(hdr.Mode))
+			if err != nil {
+				return fmt.Errorf("cannot create %s: %v", path, err)
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("cannot write %s: %v", path, err)
+			}
+		}
+	}
+}
+
+// checkLinkTarget rejects a link whose target, resolved against base,
+// would land outside dest.
+func checkLinkTarget(dest, base, linkname string) error {
+	if filepath.IsAbs(linkname) {
+		return fmt.Errorf("absolute link target %q", linkname)
+	}
+	target := filepath.Join(base, linkname)
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("link target %q escapes %s", linkname, dest)
+	}
+	return nil
+}
+
+// downloadDir pulls the contents of spec.source from client into spec.dest,
+// decompressing with the codec named by format. Extraction runs in-process
+// via extractTar rather than shelling out to tar(1). Cancelling ctx closes
+// the pipe between the receive and the extraction, so neither side stays
+// blocked on that pipe. downloadDir still waits for client.RecvTar to
+// return, and RecvTar takes no context, so a receive blocked on the remote
+// side is not interrupted by ctx.
+func downloadDir(ctx context.Context, spec downloadSpec, format string, client *spread.Client) error {
+	if err := os.MkdirAll(spec.dest, 0755); err != nil {
+		return fmt.Errorf("cannot create artifacts directory: %v", err)
+	}
+
+	pr, pw := io.Pipe()
+
+	recvDone := make(chan error, 1)
+	go func() {
+		recvErr := client.RecvTar(spec.source, []string{}, pw)
+		pw.Close()
+		recvDone <- recvErr
+	}()
+
+	extractDone := make(chan error, 1)
+	go func() {
+		dec, err := decompressor(format, pr)
+		if err != nil {
+			pr.CloseWithError(err)
+			extractDone <- err
+			return
+		}
+		defer dec.Close()
+		err = extractTar(dec, spec.dest)
+		if err != nil {
+			// Nothing reads the pipe any more; close it so the
+			// receive fails instead of blocking on its next write.
+			pr.CloseWithError(err)
+		}
+		extractDone <- err
+	}()
+
+	var recvErr, extractErr error
+	select {
+	case <-ctx.Done():
+		// Unblock both the in-flight receive and the extraction so
+		// neither goroutine is left waiting on a cancelled run.
+		pr.CloseWithError(ctx.Err())
+		pw.CloseWithError(ctx.Err())
+		recvErr, extractErr = <-recvDone, <-extractDone
+	case recvErr = <-recvDone:
+		extractErr = <-extractDone
+	}
+
+	return firstErr(recvErr, extractErr)
+}
+
+// downloadAll runs downloadDir for every -download spec collected.
+func downloadAll(ctx context.Context, client *spread.Client) error {
+	for _, spec := range downloads {
+		if err := downloadDir(ctx, spec, *downloadFormat, client); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envFlags maps flag names to the environment variable that can supply
+// their value. Precedence is explicit flag > env > default: applyEnvFlags
+// only touches flags the user didn't pass on the command line, so adding a
+// new flag to this table is all that's needed to make it env-configurable.
+var envFlags = map[string]string{
+	"pass":      "SPREAD_PASS",
+	"artifacts": "SPREAD_ARTIFACTS",
+	"logs":      "SPREAD_LOGS",
+	"reuse":     "SPREAD_REUSE",
+	"reuse-pid": "SPREAD_REUSE_PID",
+	"seed":      "SPREAD_SEED",
+	"repeat":    "SPREAD_REPEAT",
+	"debug":     "SPREAD_DEBUG",
+	"shell":     "SPREAD_SHELL",
+	"gc":        "SPREAD_GC",
+	"download":  "SPREAD_DOWNLOAD",
+}
+
+// applyEnvFlags fills in flags not passed on the command line with the
+// corresponding SPREAD_* environment variable, so spread can
+// be driven from CI systems and .env files without wrapper scripts. Flags
+// explicitly passed on the command line always win.
+func applyEnvFlags() error {
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for name, envName := range envFlags {
+		if explicit[name] {
+			continue
+		}
+		value, ok := os.LookupEnv(envName)
+		if !ok {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("cannot set -%s from %s: %v", name, envName, err)
+		}
+	}
+	return nil
+}
+
+// envFilterArgs returns the positional filter arguments to use, preferring
+// arguments given on the command line and falling back to SPREAD_FILTER.
+func envFilterArgs(args []string) []string {
+	if len(args) > 0 {
+		return args
+	}
+	if filter := os.Getenv("SPREAD_FILTER"); filter != "" {
+		return strings.Fields(filter)
+	}
+	return nil
+}
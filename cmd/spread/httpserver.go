@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// statusServer is the in-process HTTP server started by -http, letting an
+// operator poke at a long CI run without waiting for the log to scroll by.
+// It holds the same jobReportRecorder that -report/-junit write from, so
+// /jobs reads live state through the recorder's own lock instead of a
+// separately synchronized copy.
+//
+// This deliberately serves fewer routes than a full run-inspection server
+// would: live per-job log tailing and a server/address inventory both need
+// the Runner to publish state through an observer interface the HTTP
+// handler can subscribe to, and that observer doesn't exist on the Runner
+// or Client types available here, because the spread package isn't
+// vendored in this tree for those types to be extended. Rather than serve
+// those two routes as permanent 501s, they're left unregistered: a 404 for
+// "not served" is the honest response, not a stub promising work that
+// depends on a hook this binary can't add. Adding either route for real
+// means adding that observer to the spread package itself, not here.
+type statusServer struct {
+	recorder  *jobReportRecorder
+	artifacts string
+	cancel    context.CancelFunc
+	stop      func()
+}
+
+func newStatusServer(recorder *jobReportRecorder, artifacts string, cancel context.CancelFunc, stop func()) *statusServer {
+	return &statusServer{recorder: recorder, artifacts: artifacts, cancel: cancel, stop: stop}
+}
+
+func (s *statusServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", s.handleJobs)
+	mux.HandleFunc("/artifacts/", s.handleArtifacts)
+	mux.HandleFunc("/stop", s.handleStop)
+	return mux
+}
+
+func (s *statusServer) handleJobs(w http.ResponseWriter, r *http.Request) {
+	var reports []*jobReport
+	if s.recorder != nil {
+		reports = s.recorder.snapshot()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
+
+func (s *statusServer) handleArtifacts(w http.ResponseWriter, r *http.Request) {
+	if s.artifacts == "" {
+		http.Error(w, "no -artifacts directory configured for this run", http.StatusNotFound)
+		return
+	}
+	job := strings.TrimPrefix(r.URL.Path, "/artifacts/")
+	if job == "" || strings.Contains(job, "..") {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(s.artifacts, path.Clean("/"+job)))
+}
+
+func (s *statusServer) handleStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	s.cancel()
+	s.stop()
+	w.WriteHeader(http.StatusAccepted)
+}
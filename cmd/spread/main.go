@@ -1,21 +1,21 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"crypto/rand"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	mrand "math/rand"
+	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
 	"strings"
 	"time"
 
 	"github.com/niemeyer/pretty"
 	"github.com/snapcore/spread/spread"
+	spreadlog "github.com/snapcore/spread/spread/log"
 )
 
 var (
@@ -39,7 +39,9 @@ var (
 	seed           = flag.Int64("seed", 0, "Seed for job order permutation")
 	repeat         = flag.Int("repeat", 0, "Number of times to repeat each task")
 	garbageCollect = flag.Bool("gc", false, "Garbage collect backend resources when possible")
-	download       = flag.String("download", "", "Download contents of src-dir to dst-dir after all jobs have finished for each suite (Usage: -download <src-dir>:<dst-dir>)")
+	report         = flag.String("report", "", "Write a newline-delimited JSON run report to the given path")
+	junitReport    = flag.String("junit", "", "Write a JUnit XML run report to the given path")
+	httpAddr       = flag.String("http", "", "Serve /jobs, /artifacts and /stop on the given address (e.g. :8080)")
 )
 
 func firstErr(errs ...error) error {
@@ -51,36 +53,6 @@ func firstErr(errs ...error) error {
 	return nil
 }
 
-func downloadDir(downloadString string, client *spread.Client) error {
-	parts := strings.Split(downloadString, ":")
-	if len(parts) != 2 {
-		return nil
-	}
-	source := parts[0]
-	dest := parts[1]
-
-	if err := os.MkdirAll(dest, 0755); err != nil {
-		return fmt.Errorf("cannot create artifacts directory: %v", err)
-	}
-
-	tarr, tarw := io.Pipe()
-
-	var stderr bytes.Buffer
-	cmd := exec.Command("tar", "xJ")
-	cmd.Dir = dest
-	cmd.Stdin = tarr
-	cmd.Stderr = &stderr
-	err := cmd.Start()
-	if err != nil {
-		return fmt.Errorf("cannot start unpacking tar: %v", err)
-	}
-	err = client.RecvTar(source, []string{}, tarw)
-	tarw.Close()
-	terr := cmd.Wait()
-
-	return firstErr(err, terr)
-}
-
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
@@ -92,9 +64,22 @@ func run() error {
 	mrand.Seed(time.Now().UnixNano())
 	flag.Parse()
 
-	spread.Logger = log.New(os.Stdout, "", 0)
+	if err := applyEnvFlags(); err != nil {
+		return err
+	}
+
+	// Route the legacy spread.Logger/Verbose/Debug globals through the
+	// leveled spread/log sink instead of straight to stdout, so existing
+	// callers of those globals keep compiling and producing output while
+	// gaining JSON-on-non-TTY. Their records are info level with no
+	// subsystem, so SPREAD_TRACE does not filter them. -vv traces every
+	// subsystem, unless SPREAD_TRACE was set explicitly, which always wins.
+	spread.Logger = log.New(logBridge{}, "", 0)
 	spread.Verbose = *verbose
 	spread.Debug = *vverbose
+	if *vverbose && os.Getenv("SPREAD_TRACE") == "" {
+		spreadlog.SetTrace("*")
+	}
 
 	var other bool
 	for _, b := range []bool{*debug, *shell, *shellBefore || *shellAfter, *abend, *restore} {
@@ -117,7 +102,7 @@ func run() error {
 
 	var filter spread.Filter
 	var err error
-	if args := flag.Args(); len(args) > 0 {
+	if args := envFilterArgs(flag.Args()); len(args) > 0 {
 		filter, err = spread.NewFilter(args)
 		if err != nil {
 			return err
@@ -165,9 +150,39 @@ func run() error {
 		options.Reuse = true
 	}
 
-	if download != nil {
+	// Descoped: spread.Start/Runner.Wait/Client.RecvTar/SendTar are not
+	// ctx-aware, and changing that is out of reach from this binary since
+	// the spread package's source isn't part of this tree to modify.
+	// ctx is therefore scoped to what main.go owns outright: the
+	// -download extraction below, and unblocking the pipe a download
+	// reads/writes through on the first SIGINT. A backend blocked in a
+	// long poll inside spread.Start/Runner.Wait is not reached by this
+	// ctx and will not be interrupted by it — only a second SIGINT gets
+	// the user out of that case, via os.Exit. Making the first SIGINT
+	// actually cancel in-flight backend work requires threading a
+	// context.Context through spread.Start/Runner/Client's own
+	// signatures, which belongs in the spread package itself, not here.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var recorder *jobReportRecorder
+	if *report != "" || *junitReport != "" || *httpAddr != "" {
+		jobs, err := project.Jobs(options)
+		if err != nil {
+			return err
+		}
+		recorder = newJobReportRecorder(jobs)
+	}
+
+	if len(downloads) > 0 || recorder != nil {
 		project.PreRestoreProject = func(client *spread.Client, job *spread.Job, project *spread.Project) error {
-			return downloadDir(*download, client)
+			if recorder != nil {
+				recorder.recordRestore(job.Name, time.Now())
+			}
+			if len(downloads) > 0 {
+				return downloadAll(ctx, client)
+			}
+			return nil
 		}
 	}
 
@@ -176,14 +191,50 @@ func run() error {
 		return err
 	}
 
-	sigch := make(chan os.Signal, 1)
+	if *httpAddr != "" {
+		srv := newStatusServer(recorder, *artifacts, cancel, runner.Stop)
+		go func() {
+			if err := http.ListenAndServe(*httpAddr, srv.handler()); err != nil {
+				fmt.Fprintf(os.Stderr, "spread: http status server stopped: %v\n", err)
+			}
+		}()
+	}
+
+	sigch := make(chan os.Signal, 2)
 	signal.Notify(sigch, os.Interrupt)
 	go func() {
 		<-sigch
+		// First Ctrl-C: cancel the locally-owned ctx (unblocking any
+		// in-flight -download) and ask the runner to stop, which lets
+		// backends still run their restore scripts rather than leaking
+		// resources. This does not interrupt a backend already blocked
+		// inside spread.Start/Runner.Wait; see the ctx comment above.
+		cancel()
 		runner.Stop()
+
+		<-sigch
+		// Second Ctrl-C: the user wants out now, restore scripts and all.
+		fmt.Fprintln(os.Stderr, "spread: second interrupt received, abending")
+		os.Exit(1)
 	}()
 
-	return runner.Wait()
+	runErr := runner.Wait()
+
+	if recorder != nil {
+		reports := recorder.snapshot()
+		if *report != "" {
+			if err := writeJSONReport(*report, reports); err != nil {
+				return err
+			}
+		}
+		if *junitReport != "" {
+			if err := writeJUnitReport(*junitReport, reports, runErr); err != nil {
+				return err
+			}
+		}
+	}
+
+	return runErr
 }
 
 func printf(format string, v ...interface{}) {
@@ -192,6 +243,19 @@ func printf(format string, v ...interface{}) {
 	}
 }
 
+// logBridge adapts the standard *log.Logger handed to spread.Logger into
+// the leveled spread/log sink, so output from spread.Logger.Output (used
+// throughout the spread package and by printf above) goes through the
+// active sink, JSON-on-non-TTY included, instead of writing straight to
+// stdout. Every line is logged with Infof on the subsystem-less logger, so
+// SPREAD_TRACE does not filter it.
+type logBridge struct{}
+
+func (logBridge) Write(p []byte) (int, error) {
+	spreadlog.Infof("%s", strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
 func parseReuseEntry(entry string) (backend string, addrs []string) {
 	if i := strings.Index(entry, ":"); i > 0 {
 		return entry[:i], strings.Split(entry[i+1:], ",")
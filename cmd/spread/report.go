@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snapcore/spread/spread"
+)
+
+// jobReport is the per-job record written to -report and -junit. Job names
+// follow the "backend:system:suite/task:variant" convention, so most of the
+// fields below are derived from spread.Job.Name rather than duplicated
+// storage on the job itself.
+//
+// Status is deliberately neutral ("executed" or "not-run"), not pass/fail:
+// the only per-job signal available is Project.PreRestoreProject, and
+// spread runs a task's restore phase whether or not its execution failed,
+// so "reached restore" cannot be read as "passed". Getting that wrong in
+// the JUnit output would report failing tasks as green, which is worse
+// than not reporting them at all.
+type jobReport struct {
+	Name     string `json:"name"`
+	Backend  string `json:"backend"`
+	System   string `json:"system"`
+	Suite    string `json:"suite"`
+	Task     string `json:"task"`
+	Variant  string `json:"variant"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+}
+
+// splitJobName breaks a "backend:system:suite/task:variant" job name into
+// its components. Any part that doesn't fit the convention is left empty
+// rather than guessed at.
+func splitJobName(name string) (backend, system, suite, task, variant string) {
+	fields := strings.Split(name, ":")
+	if len(fields) > 0 {
+		backend = fields[0]
+	}
+	if len(fields) > 1 {
+		system = fields[1]
+	}
+	if len(fields) > 2 {
+		suiteTask := fields[2]
+		if i := strings.LastIndex(suiteTask, "/"); i >= 0 {
+			suite, task = suiteTask[:i], suiteTask[i+1:]
+		} else {
+			task = suiteTask
+		}
+	}
+	if len(fields) > 3 {
+		variant = fields[3]
+	}
+	return
+}
+
+// jobReportRecorder is the single source of truth behind -report, -junit
+// and the /jobs endpoint. The only per-job signal the public Runner API
+// currently exposes to this binary is Project.PreRestoreProject, which
+// fires once per attempt as a job enters its restore phase, so that's what
+// drives Attempts above. It cannot report pass/fail, durations, prepare/
+// execute timings or stderr tails without a richer Reporter hook into the
+// runner, which doesn't exist in this tree yet.
+type jobReportRecorder struct {
+	mu     sync.Mutex
+	order  []string
+	byName map[string]*jobReport
+}
+
+func newJobReportRecorder(jobs []*spread.Job) *jobReportRecorder {
+	rec := &jobReportRecorder{byName: make(map[string]*jobReport, len(jobs))}
+	for _, job := range jobs {
+		backend, system, suite, task, variant := splitJobName(job.Name)
+		rec.byName[job.Name] = &jobReport{
+			Name:    job.Name,
+			Backend: backend,
+			System:  system,
+			Suite:   suite,
+			Task:    task,
+			Variant: variant,
+			Status:  "not-run",
+		}
+		rec.order = append(rec.order, job.Name)
+	}
+	return rec
+}
+
+// recordRestore marks name as having reached its restore phase, incrementing
+// its attempt count. Call it from Project.PreRestoreProject, which the
+// runner invokes once per attempt, on both successful and failed tasks.
+func (rec *jobReportRecorder) recordRestore(name string, when time.Time) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	r, ok := rec.byName[name]
+	if !ok {
+		return
+	}
+	r.Attempts++
+	r.Status = "executed"
+}
+
+// snapshot returns a point-in-time copy of every job report, in job order,
+// safe to read or write without racing the recorder's own updates.
+func (rec *jobReportRecorder) snapshot() []*jobReport {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	out := make([]*jobReport, len(rec.order))
+	for i, name := range rec.order {
+		cp := *rec.byName[name]
+		out[i] = &cp
+	}
+	return out
+}
+
+// writeJSONReport writes one JSON object per job, newline-delimited. It is
+// called once, after the run has finished; nothing is written to the file
+// while jobs are still running.
+func writeJSONReport(path string, reports []*jobReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create report file: %v", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, r := range reports {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("cannot write report entry: %v", err)
+		}
+	}
+	return nil
+}
+
+type junitTestSuite struct {
+	XMLName    xml.Name        `xml:"testsuite"`
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnitReport writes a JUnit XML document compatible with Jenkins and
+// GitLab's test report ingestion. It never asserts <failure>: the recorder
+// has no way to tell a passing task from a failing one that still ran its
+// restore script, so claiming otherwise would silently turn failed CI runs
+// green. "not-run" jobs are reported <skipped/>, which is a fact ("this
+// didn't reach restore"), not a pass/fail claim. If the overall run
+// returned an error, that's surfaced as a suite-level property instead of
+// being pinned on any one job.
+func writeJUnitReport(path string, reports []*jobReport, runErr error) error {
+	suite := junitTestSuite{Name: "spread"}
+	if runErr != nil {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "run.error", Value: runErr.Error()})
+	}
+	for _, r := range reports {
+		suite.Tests++
+		tc := junitTestCase{
+			ClassName: fmt.Sprintf("%s.%s.%s", r.Backend, r.System, r.Suite),
+			Name:      fmt.Sprintf("%s:%s (attempt %d)", r.Task, r.Variant, r.Attempts),
+		}
+		if r.Status == "not-run" {
+			suite.Skipped++
+			tc.Skipped = &junitSkipped{Message: "job never reached its restore phase"}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create junit report file: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := io.WriteString(f, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(f)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return fmt.Errorf("cannot write junit report: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+// Package log is spread's leveled, pluggable logging subsystem. It replaces
+// the single free-text stream previously produced via the package-level
+// spread.Logger, spread.Verbose and spread.Debug globals with per-subsystem
+// filtering and structured fields, so triaging a failure across dozens of
+// concurrent backends doesn't mean scrolling through one undifferentiated
+// log.
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log record, lowest to highest.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Fields are the structured key=value pairs attached to a record, such as
+// job name, backend, system or attempt.
+type Fields map[string]interface{}
+
+// Record is a single log event, passed to a Sink.
+type Record struct {
+	Time      time.Time
+	Level     Level
+	Subsystem string
+	Message   string
+	Fields    Fields
+}
+
+// Sink receives log records. Tests can install their own Sink to capture
+// records instead of asserting against rendered text.
+type Sink interface {
+	Log(Record)
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(Record)
+
+func (f SinkFunc) Log(r Record) { f(r) }
+
+// textSink renders records as "time LEVEL subsystem: message key=value ...",
+// one line per record.
+type textSink struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (s *textSink) Log(r Record) {
+	var b strings.Builder
+	b.WriteString(r.Time.Format("15:04:05.000"))
+	b.WriteByte(' ')
+	b.WriteString(strings.ToUpper(r.Level.String()))
+	if r.Subsystem != "" {
+		b.WriteByte(' ')
+		b.WriteString(r.Subsystem)
+	}
+	b.WriteString(": ")
+	b.WriteString(r.Message)
+	for _, k := range sortedKeys(r.Fields) {
+		fmt.Fprintf(&b, " %s=%v", k, r.Fields[k])
+	}
+	b.WriteByte('\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	io.WriteString(s.out, b.String())
+}
+
+// jsonSink renders one JSON object per record, used when stdout isn't a
+// terminal so external tooling can consume structured output.
+type jsonSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (s *jsonSink) Log(r Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enc.Encode(struct {
+		Time      time.Time `json:"time"`
+		Level     string    `json:"level"`
+		Subsystem string    `json:"subsystem,omitempty"`
+		Message   string    `json:"message"`
+		Fields    Fields    `json:"fields,omitempty"`
+	}{r.Time, r.Level.String(), r.Subsystem, r.Message, r.Fields})
+}
+
+func sortedKeys(f Fields) []string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j-1] > keys[j]; j-- {
+			keys[j-1], keys[j] = keys[j], keys[j-1]
+		}
+	}
+	return keys
+}
+
+var (
+	mu               sync.RWMutex
+	sink             Sink = &textSink{out: os.Stdout}
+	traceAll, traced      = parseTrace(os.Getenv("SPREAD_TRACE"))
+)
+
+func init() {
+	if fi, err := os.Stdout.Stat(); err == nil && fi.Mode()&os.ModeCharDevice == 0 {
+		SetJSONOutput(os.Stdout)
+	}
+}
+
+// parseTrace turns a SPREAD_TRACE value into the set of subsystems to debug
+// trace. An unset or empty value traces nothing, matching spread's default
+// of being quiet unless asked otherwise; "*" (or "all") traces everything.
+func parseTrace(v string) (all bool, subsystems map[string]bool) {
+	m := make(map[string]bool)
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+		case "*", "all":
+			all = true
+		default:
+			m[name] = true
+		}
+	}
+	return all, m
+}
+
+// SetSink replaces the active sink. Tests use this to capture records.
+func SetSink(s Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+	sink = s
+}
+
+// SetJSONOutput switches to newline-delimited JSON records written to w.
+func SetJSONOutput(w io.Writer) {
+	SetSink(&jsonSink{enc: json.NewEncoder(w)})
+}
+
+// SetTrace overrides which subsystems emit debug-level records, as if
+// SPREAD_TRACE had been set to a comma-separated list of subsystem names.
+// No arguments disables debug output entirely; "*" enables it for every
+// subsystem.
+func SetTrace(subsystems ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	traceAll, traced = parseTrace(strings.Join(subsystems, ","))
+}
+
+func emit(level Level, subsystem, format string, fields Fields, args []interface{}) {
+	if level == LevelDebug {
+		mu.RLock()
+		enabled := traceAll || traced[subsystem]
+		mu.RUnlock()
+		if !enabled {
+			return
+		}
+	}
+	mu.RLock()
+	s := sink
+	mu.RUnlock()
+	s.Log(Record{
+		Time:      time.Now(),
+		Level:     level,
+		Subsystem: subsystem,
+		Message:   fmt.Sprintf(format, args...),
+		Fields:    fields,
+	})
+}
+
+// Logger is a per-subsystem logger carrying a fixed set of fields (job
+// name, backend, system, attempt, ...) that get attached to every record it
+// emits.
+type Logger struct {
+	subsystem string
+	fields    Fields
+}
+
+// New returns a Logger for the given subsystem, used as the SPREAD_TRACE
+// name (e.g. "ssh", "lxd", "google").
+func New(subsystem string) *Logger {
+	return &Logger{subsystem: subsystem}
+}
+
+// With returns a copy of the Logger with the given fields merged in.
+func (l *Logger) With(fields Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Logger{subsystem: l.subsystem, fields: merged}
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	emit(LevelDebug, l.subsystem, format, l.fields, args)
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	emit(LevelInfo, l.subsystem, format, l.fields, args)
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	emit(LevelWarn, l.subsystem, format, l.fields, args)
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	emit(LevelError, l.subsystem, format, l.fields, args)
+}
+
+var std = New("")
+
+// Debugf, Infof, Warnf and Errorf log on the default, subsystem-less
+// Logger. Backends that want SPREAD_TRACE filtering should use New instead.
+func Debugf(format string, args ...interface{}) { std.Debugf(format, args...) }
+func Infof(format string, args ...interface{})  { std.Infof(format, args...) }
+func Warnf(format string, args ...interface{})  { std.Warnf(format, args...) }
+func Errorf(format string, args ...interface{}) { std.Errorf(format, args...) }
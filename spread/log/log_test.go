@@ -0,0 +1,38 @@
+package log
+
+import "testing"
+
+func TestTraceFiltersDebugBySubsystem(t *testing.T) {
+	var got []Record
+	SetSink(SinkFunc(func(r Record) { got = append(got, r) }))
+	defer SetSink(&textSink{out: discard{}})
+
+	SetTrace("lxd")
+	defer SetTrace()
+
+	New("ssh").Debugf("dialing %s", "example.com")
+	New("lxd").Debugf("launching %s", "container")
+
+	if len(got) != 1 {
+		t.Fatalf("expected 1 record after filtering, got %d", len(got))
+	}
+	if got[0].Subsystem != "lxd" {
+		t.Fatalf("expected lxd record, got %q", got[0].Subsystem)
+	}
+}
+
+func TestLoggerWithMergesFields(t *testing.T) {
+	var got Record
+	SetSink(SinkFunc(func(r Record) { got = r }))
+	defer SetSink(&textSink{out: discard{}})
+
+	New("google").With(Fields{"job": "suite/task"}).Infof("starting")
+
+	if got.Fields["job"] != "suite/task" {
+		t.Fatalf("expected job field to be set, got %v", got.Fields)
+	}
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }